@@ -0,0 +1,138 @@
+package gofs
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+/*
+AuthTokens holds the bearer tokens gofs will accept, split by whether they
+grant read-only or read-write access.
+*/
+type AuthTokens struct {
+	ReadOnly  []string
+	ReadWrite []string
+}
+
+func (t AuthTokens) empty() bool {
+	return len(t.ReadOnly) == 0 && len(t.ReadWrite) == 0
+}
+
+/*
+authMiddleware enforces bearer tokens and/or HMAC-signed URLs on every
+request. GETs are allowed through unchecked only when neither read-only
+tokens nor a signing key are configured. Once they are, a GET requires a
+read-only or read-write token or a valid signature; anything that mutates
+state always requires a read-write token or a valid signature.
+*/
+func authMiddleware(tokens AuthTokens, signingKey string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		readWrite := ctx.Request.Method != http.MethodGet
+
+		if !readWrite && len(tokens.ReadOnly) == 0 && signingKey == "" {
+			ctx.Next()
+			return
+		}
+
+		if checkBearer(ctx, tokens, readWrite) {
+			ctx.Next()
+			return
+		}
+
+		if signingKey != "" && checkSignature(ctx, signingKey) {
+			ctx.Next()
+			return
+		}
+
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+	}
+}
+
+func checkBearer(ctx *gin.Context, tokens AuthTokens, readWrite bool) bool {
+	token, found := strings.CutPrefix(ctx.GetHeader("Authorization"), "Bearer ")
+	if !found || token == "" {
+		return false
+	}
+
+	if tokenMatches(token, tokens.ReadWrite) {
+		return true
+	}
+	return !readWrite && tokenMatches(token, tokens.ReadOnly)
+}
+
+func tokenMatches(token string, candidates []string) bool {
+	for _, candidate := range candidates {
+		if subtle.ConstantTimeCompare([]byte(candidate), []byte(token)) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+func checkSignature(ctx *gin.Context, signingKey string) bool {
+	expParam := ctx.Query("exp")
+	sig := ctx.Query("sig")
+	if expParam == "" || sig == "" {
+		return false
+	}
+
+	exp, err := strconv.ParseInt(expParam, 10, 64)
+	if err != nil || time.Now().Unix() > exp {
+		return false
+	}
+
+	expected := signURL(signingKey, ctx.Request.Method, ctx.Request.URL.Path, exp)
+	return hmac.Equal([]byte(expected), []byte(sig))
+}
+
+/*
+signURL computes the hex-encoded HMAC-SHA256 signature for a method, path and
+expiry under signingKey.
+*/
+func signURL(signingKey, method, path string, exp int64) string {
+	mac := hmac.New(sha256.New, []byte(signingKey))
+	fmt.Fprintf(mac, "%s\n%s\n%d", method, path, exp)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+/*
+SignPath returns the "exp=...&sig=..." query string granting access to path
+via method until ttl from now, for use with an Opts.SigningKey server.
+*/
+func SignPath(signingKey, method, path string, ttl time.Duration) string {
+	exp := time.Now().Add(ttl).Unix()
+	return fmt.Sprintf("exp=%d&sig=%s", exp, signURL(signingKey, method, path, exp))
+}
+
+/*
+loadTokenFile reads newline-separated bearer tokens from path, ignoring
+blank lines. It returns nil, nil if path is empty.
+*/
+func loadTokenFile(path string) ([]string, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var tokens []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			tokens = append(tokens, line)
+		}
+	}
+	return tokens, nil
+}
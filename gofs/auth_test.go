@@ -0,0 +1,65 @@
+package gofs
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+func doAuthRequest(tokens AuthTokens, signingKey, method, target string, headers map[string]string) int {
+	router := gin.New()
+	router.Use(authMiddleware(tokens, signingKey))
+	router.Handle(method, "/*addr", func(ctx *gin.Context) {
+		ctx.String(http.StatusOK, "ok")
+	})
+
+	req := httptest.NewRequest(method, target, nil)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func TestAuthMiddleware_ReadWriteTokenRequiredForWrites(t *testing.T) {
+	tokens := AuthTokens{ReadWrite: []string{"rw-token"}}
+
+	assert.Equal(t, http.StatusUnauthorized, doAuthRequest(tokens, "", http.MethodPost, "/foo", nil))
+	assert.Equal(t, http.StatusOK, doAuthRequest(tokens, "", http.MethodPost, "/foo", map[string]string{
+		"Authorization": "Bearer rw-token",
+	}))
+}
+
+func TestAuthMiddleware_ReadOnlyTokenGatesGETsButNotWrites(t *testing.T) {
+	tokens := AuthTokens{ReadOnly: []string{"ro-token"}}
+
+	assert.Equal(t, http.StatusUnauthorized, doAuthRequest(tokens, "", http.MethodGet, "/foo", nil))
+	assert.Equal(t, http.StatusOK, doAuthRequest(tokens, "", http.MethodGet, "/foo", map[string]string{
+		"Authorization": "Bearer ro-token",
+	}))
+	assert.Equal(t, http.StatusUnauthorized, doAuthRequest(tokens, "", http.MethodPost, "/foo", map[string]string{
+		"Authorization": "Bearer ro-token",
+	}))
+}
+
+func TestAuthMiddleware_SignedURLGatesGETsOnceSigningKeyIsSet(t *testing.T) {
+	const signingKey = "secret"
+	qs := SignPath(signingKey, http.MethodGet, "/foo", time.Hour)
+
+	assert.Equal(t, http.StatusUnauthorized, doAuthRequest(AuthTokens{}, signingKey, http.MethodGet, "/foo", nil))
+	assert.Equal(t, http.StatusOK, doAuthRequest(AuthTokens{}, signingKey, http.MethodGet, "/foo?"+qs, nil))
+}
+
+func TestAuthMiddleware_OpenWhenUnconfigured(t *testing.T) {
+	assert.Equal(t, http.StatusOK, doAuthRequest(AuthTokens{}, "", http.MethodGet, "/foo", nil))
+}
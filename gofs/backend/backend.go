@@ -0,0 +1,41 @@
+package backend
+
+import (
+	"errors"
+	"io"
+	"time"
+)
+
+/*
+ErrForbidden is returned by a Backend when a given path resolves outside of
+the root it was constructed with, e.g. via a crafted "../" segment or a
+symlink planted inside the root that points out of it.
+*/
+var ErrForbidden = errors.New("path escapes backend root")
+
+/*
+Info describes a single file or directory entry, independent of which
+Backend produced it.
+*/
+type Info struct {
+	Name    string
+	Size    int64
+	ModTime time.Time
+	IsDir   bool
+	ETag    string
+}
+
+/*
+Backend abstracts the storage operations gofs needs so that the server can
+serve and accept uploads against a local directory, an S3-compatible bucket,
+or any other store that implements it, interchangeably. Paths are always
+slash-separated and relative to whatever root the Backend was constructed
+with.
+*/
+type Backend interface {
+	Stat(path string) (Info, error)
+	Open(path string) (io.ReadCloser, error)
+	Create(path string) (io.WriteCloser, error)
+	ReadDir(path string) ([]Info, error)
+	RemoveAll(path string) error
+}
@@ -0,0 +1,152 @@
+package backend
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type osBackend struct {
+	root string
+}
+
+/*
+NewOSBackend creates a Backend rooted at root on the local filesystem. This
+is the default backend gofs uses when none is configured.
+*/
+func NewOSBackend(root string) Backend {
+	return &osBackend{root: filepath.Clean(root)}
+}
+
+/*
+resolve joins path onto the backend's root and verifies the result cannot
+escape it, whether via a crafted "../" segment or a symlink planted inside
+the root that points outside of it.
+*/
+func (b *osBackend) resolve(path string) (string, error) {
+	full := filepath.Join(b.root, path)
+	if !underRoot(b.root, full) {
+		return "", ErrForbidden
+	}
+
+	resolvedRoot, err := filepath.EvalSymlinks(b.root)
+	if err != nil {
+		return "", err
+	}
+
+	resolvedBase, remainder, err := evalExistingPrefix(full)
+	if err != nil {
+		return "", err
+	}
+
+	resolved := filepath.Join(resolvedBase, remainder)
+	if !underRoot(resolvedRoot, resolved) {
+		return "", ErrForbidden
+	}
+	return full, nil
+}
+
+func underRoot(root, path string) bool {
+	return path == root || strings.HasPrefix(path, root+string(os.PathSeparator))
+}
+
+/*
+evalExistingPrefix resolves symlinks on the longest existing prefix of path,
+then returns it alongside whatever trailing segments don't exist yet, so a
+not-yet-created upload target can still be checked without requiring
+filepath.EvalSymlinks on a nonexistent path.
+*/
+func evalExistingPrefix(path string) (base string, remainder string, err error) {
+	cur := path
+	for {
+		resolved, statErr := filepath.EvalSymlinks(cur)
+		if statErr == nil {
+			return resolved, remainder, nil
+		}
+		if !os.IsNotExist(statErr) {
+			return "", "", statErr
+		}
+		// cur exists but can't be resolved: a dangling symlink, whose target
+		// can't be checked, so it is refused rather than followed.
+		if _, lstatErr := os.Lstat(cur); lstatErr == nil {
+			return "", "", ErrForbidden
+		}
+
+		parent := filepath.Dir(cur)
+		if parent == cur {
+			return cur, remainder, nil
+		}
+		remainder = filepath.Join(filepath.Base(cur), remainder)
+		cur = parent
+	}
+}
+
+func (b *osBackend) Stat(path string) (Info, error) {
+	full, err := b.resolve(path)
+	if err != nil {
+		return Info{}, err
+	}
+
+	inf, err := os.Stat(full)
+	if err != nil {
+		return Info{}, err
+	}
+
+	return Info{Name: inf.Name(), Size: inf.Size(), ModTime: inf.ModTime(), IsDir: inf.IsDir()}, nil
+}
+
+func (b *osBackend) Open(path string) (io.ReadCloser, error) {
+	full, err := b.resolve(path)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(full)
+}
+
+func (b *osBackend) Create(path string) (io.WriteCloser, error) {
+	full, err := b.resolve(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(full), os.ModePerm); err != nil {
+		return nil, err
+	}
+
+	return os.OpenFile(full, os.O_CREATE|os.O_RDWR|os.O_TRUNC, os.ModePerm)
+}
+
+func (b *osBackend) ReadDir(path string) ([]Info, error) {
+	full, err := b.resolve(path)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(full)
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]Info, 0, len(entries))
+	for _, entry := range entries {
+		inf, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+
+		infos = append(infos, Info{Name: inf.Name(), Size: inf.Size(), ModTime: inf.ModTime(), IsDir: inf.IsDir()})
+	}
+	return infos, nil
+}
+
+func (b *osBackend) RemoveAll(path string) error {
+	full, err := b.resolve(path)
+	if err != nil {
+		return err
+	}
+	if full == b.root {
+		return ErrForbidden
+	}
+	return os.RemoveAll(full)
+}
@@ -0,0 +1,40 @@
+package backend
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOSBackend_ResolveRejectsTraversal(t *testing.T) {
+	root := t.TempDir()
+	b := NewOSBackend(root).(*osBackend)
+
+	_, err := b.resolve("../escape.txt")
+	assert.ErrorIs(t, err, ErrForbidden)
+}
+
+func TestOSBackend_ResolveRejectsSymlinkEscape(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+
+	if err := os.Symlink(outside, filepath.Join(root, "link")); err != nil {
+		t.Fatalf("failed to create symlink: %v", err)
+	}
+
+	b := NewOSBackend(root).(*osBackend)
+
+	_, err := b.resolve("link/escape.txt")
+	assert.ErrorIs(t, err, ErrForbidden)
+}
+
+func TestOSBackend_ResolveAllowsPathsWithinRoot(t *testing.T) {
+	root := t.TempDir()
+	b := NewOSBackend(root).(*osBackend)
+
+	full, err := b.resolve(filepath.Join("foo", "bar.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(root, "foo", "bar.txt"), full)
+}
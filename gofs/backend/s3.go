@@ -0,0 +1,171 @@
+package backend
+
+import (
+	"context"
+	"io"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+type s3Backend struct {
+	client *minio.Client
+	bucket string
+}
+
+/*
+NewS3Backend creates a Backend that reads and writes objects in an
+S3-compatible bucket at endpoint, so gofs can serve and accept uploads
+against an object store exactly like it does against a local directory.
+*/
+func NewS3Backend(endpoint, bucket, accessKey, secretKey string, useSSL bool) (Backend, error) {
+	client, err := minio.New(endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(accessKey, secretKey, ""),
+		Secure: useSSL,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &s3Backend{client: client, bucket: bucket}, nil
+}
+
+func (b *s3Backend) key(path string) string {
+	return strings.TrimPrefix(path, "/")
+}
+
+func (b *s3Backend) Stat(path string) (Info, error) {
+	inf, err := b.client.StatObject(context.Background(), b.bucket, b.key(path), minio.StatObjectOptions{})
+	if err == nil {
+		return Info{Name: path, Size: inf.Size, ModTime: inf.LastModified, ETag: inf.ETag}, nil
+	}
+
+	if isDir, dirErr := b.isPrefix(path); dirErr == nil && isDir {
+		return Info{Name: path, IsDir: true}, nil
+	}
+
+	return Info{}, err
+}
+
+/*
+isPrefix reports whether path has any objects beneath it, since S3 has no
+directory objects of its own — a "directory" only exists in the sense
+that keys share it as a prefix.
+*/
+func (b *s3Backend) isPrefix(path string) (bool, error) {
+	prefix := b.key(path)
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+
+	ctx := context.Background()
+	for obj := range b.client.ListObjects(ctx, b.bucket, minio.ListObjectsOptions{Prefix: prefix, Recursive: false, MaxKeys: 1}) {
+		if obj.Err != nil {
+			return false, obj.Err
+		}
+		return true, nil
+	}
+	return false, nil
+}
+
+func (b *s3Backend) Open(path string) (io.ReadCloser, error) {
+	return b.client.GetObject(context.Background(), b.bucket, b.key(path), minio.GetObjectOptions{})
+}
+
+type s3Writer struct {
+	pw   *io.PipeWriter
+	done chan error
+}
+
+func (w *s3Writer) Write(p []byte) (int, error) {
+	return w.pw.Write(p)
+}
+
+func (w *s3Writer) Close() error {
+	if err := w.pw.Close(); err != nil {
+		return err
+	}
+	return <-w.done
+}
+
+func (b *s3Backend) Create(path string) (io.WriteCloser, error) {
+	pr, pw := io.Pipe()
+	done := make(chan error, 1)
+
+	go func() {
+		_, err := b.client.PutObject(context.Background(), b.bucket, b.key(path), pr, -1, minio.PutObjectOptions{})
+		pr.CloseWithError(err)
+		done <- err
+	}()
+
+	return &s3Writer{pw: pw, done: done}, nil
+}
+
+/*
+ReadDir maps to ListObjectsV2 with the "/" delimiter, so nested keys show up
+as a single directory entry rather than being listed recursively.
+*/
+func (b *s3Backend) ReadDir(path string) ([]Info, error) {
+	prefix := b.key(path)
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+
+	ctx := context.Background()
+	var infos []Info
+	for obj := range b.client.ListObjects(ctx, b.bucket, minio.ListObjectsOptions{Prefix: prefix, Recursive: false}) {
+		if obj.Err != nil {
+			return nil, obj.Err
+		}
+
+		name := strings.TrimSuffix(strings.TrimPrefix(obj.Key, prefix), "/")
+		infos = append(infos, Info{
+			Name:    name,
+			Size:    obj.Size,
+			ModTime: obj.LastModified,
+			IsDir:   strings.HasSuffix(obj.Key, "/"),
+			ETag:    obj.ETag,
+		})
+	}
+	return infos, nil
+}
+
+/*
+RemoveAll deletes the exact key at path if one exists, or everything under
+it as a directory prefix otherwise. An empty prefix (the bucket root) is
+rejected, mirroring osBackend's refusal to remove its own root.
+*/
+func (b *s3Backend) RemoveAll(path string) error {
+	ctx := context.Background()
+	key := b.key(path)
+	if key == "" {
+		return ErrForbidden
+	}
+
+	if _, err := b.client.StatObject(ctx, b.bucket, key, minio.StatObjectOptions{}); err == nil {
+		return b.client.RemoveObject(ctx, b.bucket, key, minio.RemoveObjectOptions{})
+	}
+
+	prefix := key
+	if !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+
+	objectsCh := make(chan minio.ObjectInfo)
+	go func() {
+		defer close(objectsCh)
+		for obj := range b.client.ListObjects(ctx, b.bucket, minio.ListObjectsOptions{Prefix: prefix, Recursive: true}) {
+			if obj.Err == nil {
+				objectsCh <- obj
+			}
+		}
+	}()
+
+	for removeErr := range b.client.RemoveObjects(ctx, b.bucket, objectsCh, minio.RemoveObjectsOptions{}) {
+		if removeErr.Err != nil {
+			return removeErr.Err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package backend
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"path"
+)
+
+/*
+ZipDir recursively zips every file found under dir as read through b, using
+forward-slash paths relative to dir as the zip entry names. It works for any
+Backend, so a "directory" download looks the same whether it is backed by a
+local filesystem or an object store.
+*/
+func ZipDir(b Backend, dir string) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	zw := zip.NewWriter(buf)
+
+	if err := zipWalk(b, dir, "", zw); err != nil {
+		return nil, err
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func zipWalk(b Backend, dir, rel string, zw *zip.Writer) error {
+	entries, err := b.ReadDir(path.Join(dir, rel))
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		entryRel := path.Join(rel, entry.Name)
+		if entry.IsDir {
+			if err := zipWalk(b, dir, entryRel, zw); err != nil {
+				return err
+			}
+			continue
+		}
+
+		w, err := zw.Create(entryRel)
+		if err != nil {
+			return err
+		}
+
+		r, err := b.Open(path.Join(dir, entryRel))
+		if err != nil {
+			return err
+		}
+
+		_, err = io.Copy(w, r)
+		r.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/*
+UnzipToBackend extracts the zip archive in data, writing each file it
+contains under destPrefix via b.Create.
+*/
+func UnzipToBackend(b Backend, destPrefix string, data []byte) error {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return err
+	}
+
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+
+		if err := unzipFile(b, destPrefix, f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func unzipFile(b Backend, destPrefix string, f *zip.File) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	w, err := b.Create(path.Join(destPrefix, f.Name))
+	if err != nil {
+		return err
+	}
+
+	if _, err = io.Copy(w, rc); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
@@ -0,0 +1,135 @@
+package gofs
+
+import (
+	"container/list"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+/*
+CacheEntry is a single cached record for a path: its bytes, the weak ETag
+computed for them, the mtime of the file they were read from, and their size
+in bytes.
+*/
+type CacheEntry struct {
+	Data  []byte
+	ETag  string
+	MTime time.Time
+	Size  int64
+}
+
+type cacheItem struct {
+	key   string
+	entry CacheEntry
+}
+
+/*
+Cache is a size-bounded, mtime-aware LRU cache keyed by filesystem path. A
+zero maxBytes or maxEntries means that dimension is unbounded.
+*/
+type Cache struct {
+	mu         sync.Mutex
+	maxBytes   int64
+	maxEntries int
+	curBytes   int64
+	ll         *list.List
+	items      map[string]*list.Element
+}
+
+/*
+NewCache creates an empty Cache capped at maxBytes total entry size and
+maxEntries entries. A value of 0 leaves that dimension unbounded.
+*/
+func NewCache(maxBytes int64, maxEntries int) *Cache {
+	return &Cache{
+		maxBytes:   maxBytes,
+		maxEntries: maxEntries,
+		ll:         list.New(),
+		items:      make(map[string]*list.Element),
+	}
+}
+
+/*
+Get returns the entry cached for key, provided it is still fresh relative to
+mtime. An entry whose recorded mtime no longer matches is stale, is evicted,
+and is reported as a miss.
+*/
+func (c *Cache) Get(key string, mtime time.Time) (CacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, found := c.items[key]
+	if !found {
+		return CacheEntry{}, false
+	}
+
+	item := el.Value.(*cacheItem)
+	if !item.entry.MTime.Equal(mtime) {
+		c.removeElement(el)
+		return CacheEntry{}, false
+	}
+
+	c.ll.MoveToFront(el)
+	return item.entry, true
+}
+
+/*
+Put stores entry under key, evicting the least recently used entries as
+needed to stay within the configured size and count caps.
+*/
+func (c *Cache) Put(key string, entry CacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, found := c.items[key]; found {
+		c.removeElement(el)
+	}
+
+	el := c.ll.PushFront(&cacheItem{key: key, entry: entry})
+	c.items[key] = el
+	c.curBytes += entry.Size
+
+	c.evict()
+}
+
+/*
+InvalidatePrefix drops every cached entry whose key is prefix itself or lies
+under it, so a write to that path immediately shadows anything cached there.
+*/
+func (c *Cache) InvalidatePrefix(prefix string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, el := range c.items {
+		if key == prefix || strings.HasPrefix(key, prefix+string(os.PathSeparator)) {
+			c.removeElement(el)
+		}
+	}
+}
+
+/*
+Fits reports whether an entry of size bytes could ever be cached, i.e. it
+doesn't already exceed the configured byte cap on its own.
+*/
+func (c *Cache) Fits(size int64) bool {
+	return c.maxBytes <= 0 || size <= c.maxBytes
+}
+
+func (c *Cache) evict() {
+	for (c.maxEntries > 0 && c.ll.Len() > c.maxEntries) || (c.maxBytes > 0 && c.curBytes > c.maxBytes) {
+		back := c.ll.Back()
+		if back == nil {
+			return
+		}
+		c.removeElement(back)
+	}
+}
+
+func (c *Cache) removeElement(el *list.Element) {
+	item := el.Value.(*cacheItem)
+	c.ll.Remove(el)
+	delete(c.items, item.key)
+	c.curBytes -= item.entry.Size
+}
@@ -0,0 +1,125 @@
+package gofs
+
+import (
+	"fmt"
+	"hash/fnv"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/ahdekkers/gofs/gofs/backend"
+	"github.com/gin-gonic/gin"
+)
+
+/*
+weakETag formats a hash sum as a weak entity tag, e.g. W/"1a2b3c4d".
+*/
+func weakETag(sum uint64) string {
+	return fmt.Sprintf(`W/"%x"`, sum)
+}
+
+/*
+fileETag computes a weak ETag for a single file by running FNV-64a over its
+contents. The caller is responsible for seeking r back to the start before
+using it again, e.g. to serve the body.
+*/
+func fileETag(r io.Reader) (string, error) {
+	h := fnv.New64a()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+	return weakETag(h.Sum64()), nil
+}
+
+/*
+entryETag computes a weak ETag for a single directory entry from its
+metadata alone (name, size and mtime), the same way dirETag folds entries
+together, so listing a directory never has to open every file in it.
+*/
+func entryETag(entry backend.Info) string {
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%s:%d:%d", entry.Name, entry.Size, entry.ModTime.UnixNano())
+	return weakETag(h.Sum64())
+}
+
+/*
+dirETag computes a weak ETag for a directory by folding together the path,
+size and mtime of every entry beneath it (read through b), and returns the
+most recent mtime found so callers can set Last-Modified. It deliberately
+hashes metadata rather than file contents so it stays cheap on large trees,
+and works against any Backend rather than just the local filesystem.
+*/
+func dirETag(b backend.Backend, root string) (string, time.Time, error) {
+	h := fnv.New64a()
+	var latest time.Time
+
+	var walk func(rel string) error
+	walk = func(rel string) error {
+		entries, err := b.ReadDir(path.Join(root, rel))
+		if err != nil {
+			return err
+		}
+
+		for _, entry := range entries {
+			entryRel := path.Join(rel, entry.Name)
+			if entry.IsDir {
+				if err := walk(entryRel); err != nil {
+					return err
+				}
+				continue
+			}
+
+			if entry.ModTime.After(latest) {
+				latest = entry.ModTime
+			}
+			fmt.Fprintf(h, "%s:%d:%d\n", entryRel, entry.Size, entry.ModTime.UnixNano())
+		}
+		return nil
+	}
+
+	if err := walk(""); err != nil {
+		return "", time.Time{}, err
+	}
+
+	return weakETag(h.Sum64()), latest, nil
+}
+
+/*
+notModified checks the request's If-None-Match/If-Modified-Since headers
+against an already-computed etag/modTime and, if they match, writes a 304
+response and returns true. Callers use this to skip rebuilding expensive
+content (e.g. a directory zip) on a conditional request that would just be
+discarded anyway.
+*/
+func notModified(ctx *gin.Context, etag string, modTime time.Time) bool {
+	if match := ctx.GetHeader("If-None-Match"); match != "" {
+		if !etagMatches(match, etag) {
+			return false
+		}
+	} else if since := ctx.GetHeader("If-Modified-Since"); since != "" {
+		t, err := http.ParseTime(since)
+		if err != nil || modTime.Truncate(time.Second).After(t) {
+			return false
+		}
+	} else {
+		return false
+	}
+
+	ctx.Header("ETag", etag)
+	ctx.Status(http.StatusNotModified)
+	return true
+}
+
+func etagMatches(header, etag string) bool {
+	if header == "*" {
+		return true
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		if strings.TrimSpace(candidate) == etag {
+			return true
+		}
+	}
+	return false
+}
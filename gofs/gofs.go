@@ -1,16 +1,19 @@
 package gofs
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
-	"github.com/ahdekkers/go-zipdir/zipdir"
+	"github.com/ahdekkers/gofs/gofs/backend"
 	"github.com/gin-gonic/gin"
 	"github.com/hashicorp/go-hclog"
 	"io"
 	"log"
+	"mime/multipart"
 	"net/http"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 )
@@ -32,23 +35,45 @@ func (w *logWriter) Write(p []byte) (n int, err error) {
 }
 
 type Opts struct {
-	Addr          string
-	Port          int
-	RootDir       string
-	LogLevel      string
-	LogFile       string
-	NoCache       bool
-	NoDirectories bool
+	Addr               string
+	Port               int
+	RootDir            string
+	LogLevel           string
+	LogFile            string
+	NoCache            bool
+	NoDirectories      bool
+	CacheBytes         int64
+	CacheEntries       int
+	Backend            string
+	S3Endpoint         string
+	S3Bucket           string
+	S3AccessKey        string
+	S3SecretKey        string
+	TLSCert            string
+	TLSKey             string
+	AutoTLSHosts       []string
+	AutoTLSCacheDir    string
+	AuthTokens         []string
+	AuthTokensFile     string
+	ReadOnlyTokens     []string
+	ReadOnlyTokensFile string
+	SigningKey         string
+	BrowseUI           bool
 }
 
 type Server struct {
-	srv     *http.Server
-	rootDir string
-	logger  hclog.Logger
-	stopCh  chan int
-	cache   map[string][]byte
-	noCache bool
-	noDirs  bool
+	srv          *http.Server
+	challengeSrv *http.Server
+	tlsMode      tlsMode
+	tlsCert      string
+	tlsKey       string
+	store        backend.Backend
+	logger       hclog.Logger
+	stopCh       chan int
+	cache        *Cache
+	noCache      bool
+	noDirs       bool
+	browseUI     bool
 }
 
 /*
@@ -60,29 +85,50 @@ func Create(opts Opts) (*Server, error) {
 		return nil, fmt.Errorf("failed to create logger: %v", err)
 	}
 
-	err = checkIsDir(opts.RootDir)
+	store, err := createBackend(opts)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read root dir: %v", err)
+		return nil, err
 	}
 
 	server := &Server{
-		rootDir: opts.RootDir,
-		logger:  logger,
-		stopCh:  make(chan int),
-		cache:   make(map[string][]byte),
-		noCache: opts.NoCache,
-		noDirs:  opts.NoDirectories,
+		store:    store,
+		logger:   logger,
+		stopCh:   make(chan int),
+		cache:    NewCache(opts.CacheBytes, opts.CacheEntries),
+		noCache:  opts.NoCache,
+		noDirs:   opts.NoDirectories,
+		browseUI: opts.BrowseUI,
 	}
 
 	router := gin.Default()
+
+	tokens, err := loadAuthTokens(opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load auth tokens: %v", err)
+	}
+	if !tokens.empty() || opts.SigningKey != "" {
+		router.Use(authMiddleware(tokens, opts.SigningKey))
+	}
+
 	router.Handle("GET", "/entries/*addr", server.getEntries)
 	router.Handle("GET", "/content/*addr", server.getFile)
 	router.Handle("POST", "/content/*addr", server.uploadFile)
+	router.Handle("DELETE", "/content/*addr", server.deleteFile)
 
 	server.srv = &http.Server{
 		Addr:    fmt.Sprintf("%s:%d", opts.Addr, opts.Port),
 		Handler: router,
 	}
+
+	mode, cert, key, challengeSrv, err := configureTLS(server.srv, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure tls: %v", err)
+	}
+	server.tlsMode = mode
+	server.tlsCert = cert
+	server.tlsKey = key
+	server.challengeSrv = challengeSrv
+
 	return server, nil
 }
 
@@ -91,17 +137,46 @@ Start listening for requests. This call is non-blocking
 */
 func (s *Server) Start() {
 	go func() {
-		if err := s.srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		if err := s.listenAndServe(); err != nil && err != http.ErrServerClosed {
 			log.Printf("Error while starting http file server: %v", err)
 		}
 	}()
 }
 
 func (s *Server) Run() error {
-	return s.srv.ListenAndServe()
+	return s.listenAndServe()
+}
+
+/*
+listenAndServe starts the HTTP-01 challenge listener if autocert is in use,
+then serves s.srv over plain HTTP, a static cert/key pair, or autocert TLS
+depending on how the server was configured.
+*/
+func (s *Server) listenAndServe() error {
+	if s.challengeSrv != nil {
+		go func() {
+			if err := s.challengeSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Printf("Error while starting ACME HTTP-01 challenge listener: %v", err)
+			}
+		}()
+	}
+
+	switch s.tlsMode {
+	case tlsModeAuto:
+		return s.srv.ListenAndServeTLS("", "")
+	case tlsModeManual:
+		return s.srv.ListenAndServeTLS(s.tlsCert, s.tlsKey)
+	default:
+		return s.srv.ListenAndServe()
+	}
 }
 
 func (s *Server) Stop() error {
+	if s.challengeSrv != nil {
+		if err := s.challengeSrv.Shutdown(context.Background()); err != nil {
+			return err
+		}
+	}
 	if err := s.srv.Shutdown(context.Background()); err != nil {
 		return err
 	}
@@ -112,56 +187,138 @@ func (s *Server) GetAddr() string {
 	return s.srv.Addr
 }
 
+/*
+handleStoreError writes an error response for a failed Backend call. A
+backend.ErrForbidden is reported as a 403 without leaking the resolved path,
+and logged as a likely path traversal attempt; anything else is a 400 as
+before.
+*/
+func (s *Server) handleStoreError(ctx *gin.Context, err error, addr, action string) {
+	if errors.Is(err, backend.ErrForbidden) {
+		s.logger.Warn("Rejected request attempting to escape root", "addr", addr, "action", action)
+		ctx.String(http.StatusForbidden, "Forbidden")
+		return
+	}
+
+	s.logger.Warn("Failed to "+action, "error", err, "addr", addr)
+	ctx.String(http.StatusBadRequest, "Failed to %s '%s': %v", action, addr, err)
+}
+
 func (s *Server) getFile(ctx *gin.Context) {
 	fileAddr := ctx.Param("addr")
-	path := filepath.Join(s.rootDir, fileAddr)
-	s.logger.Debug("Received get file request", "addr", fileAddr, "fullPath", path)
+	s.logger.Debug("Received get file request", "addr", fileAddr)
+
+	inf, err := s.store.Stat(fileAddr)
+	if err != nil {
+		s.handleStoreError(ctx, err, fileAddr, "read file")
+		return
+	}
 
+	if inf.IsDir {
+		s.serveDir(ctx, fileAddr)
+		return
+	}
+	s.serveFile(ctx, fileAddr, inf)
+}
+
+func (s *Server) serveFile(ctx *gin.Context, path string, inf backend.Info) {
 	if !s.noCache {
-		data, found := s.cache[path]
-		if found {
-			s.logger.Info("Retrieved file data from cache")
-			ctx.Data(http.StatusOK, "application/zip", data)
+		if entry, found := s.cache.Get(path, inf.ModTime); found {
+			s.logger.Info("Retrieved file data from cache", "path", path)
+			ctx.Header("ETag", entry.ETag)
+			http.ServeContent(ctx.Writer, ctx.Request, inf.Name, entry.MTime, bytes.NewReader(entry.Data))
 			return
 		}
 	}
 
-	inf, err := os.Stat(path)
+	file, err := s.store.Open(path)
 	if err != nil {
-		s.logger.Warn("Failed to read file data", "error", err, "path", path)
-		ctx.String(http.StatusBadRequest, "Failed to read file at '%s': %v", path, err)
+		s.handleStoreError(ctx, err, path, "read file")
 		return
 	}
-
-	var data []byte
-	if inf.IsDir() {
-		if s.noDirs {
-			ctx.String(http.StatusBadRequest, "Path '%s' is a directory and noDirs flag is true", path)
-			s.logger.Warn("Path '%s' is a directory and noDirs flag is true", "path", path)
-			return
+	defer file.Close()
+
+	if seeker, ok := file.(io.ReadSeeker); ok && (s.noCache || !s.cache.Fits(inf.Size)) {
+		// Use the backend's own ETag when it has one (e.g. S3) rather than
+		// hashing the content ourselves, which would mean reading the whole
+		// body twice - once to hash it, once for ServeContent to serve it -
+		// defeating the point of streaming large or uncacheable files.
+		if inf.ETag != "" {
+			ctx.Header("ETag", inf.ETag)
 		}
 
-		data, err = zipdir.ZipToBytes(path)
-		if err != nil {
-			s.logger.Warn("Failed to zip dir", "error", err, "path", path)
-			ctx.String(http.StatusBadRequest, "Failed to zip dir '%s': %v", path, err)
-			return
-		}
+		s.logger.Info("Streaming file without buffering", "path", path, "size", inf.Size)
+		http.ServeContent(ctx.Writer, ctx.Request, inf.Name, inf.ModTime, seeker)
+		return
+	}
 
-		s.logger.Info("Successfully returned directory as zip", "path", path)
-		ctx.Data(http.StatusOK, "application/zip", data)
-	} else {
-		data, err = os.ReadFile(path)
-		if err != nil {
-			ctx.String(http.StatusBadRequest, "Failed to read file at '%s': %v", path, err)
+	data, err := io.ReadAll(file)
+	if err != nil {
+		s.logger.Warn("Failed to read file", "error", err, "path", path)
+		ctx.String(http.StatusInternalServerError, "Failed to read file '%s': %v", path, err)
+		return
+	}
+
+	etag, err := fileETag(bytes.NewReader(data))
+	if err != nil {
+		s.logger.Warn("Failed to compute file etag", "error", err, "path", path)
+		ctx.String(http.StatusInternalServerError, "Failed to read file '%s': %v", path, err)
+		return
+	}
+
+	ctx.Header("ETag", etag)
+	s.logger.Info("Successfully returned file as raw data", "path", path)
+	http.ServeContent(ctx.Writer, ctx.Request, inf.Name, inf.ModTime, bytes.NewReader(data))
+
+	if !s.noCache {
+		s.cache.Put(path, CacheEntry{Data: data, ETag: etag, MTime: inf.ModTime, Size: int64(len(data))})
+	}
+}
+
+func (s *Server) serveDir(ctx *gin.Context, path string) {
+	if s.noDirs {
+		ctx.String(http.StatusBadRequest, "Path '%s' is a directory and noDirs flag is true", path)
+		s.logger.Warn("Path '%s' is a directory and noDirs flag is true", "path", path)
+		return
+	}
+
+	etag, modTime, err := dirETag(s.store, path)
+	if err != nil {
+		s.handleStoreError(ctx, err, path, "read dir")
+		return
+	}
+
+	name := filepath.Base(path) + ".zip"
+
+	if !s.noCache {
+		if entry, found := s.cache.Get(path, modTime); found && entry.ETag == etag {
+			s.logger.Info("Retrieved directory zip from cache", "path", path)
+			ctx.Header("ETag", entry.ETag)
+			ctx.Header("Content-Type", "application/zip")
+			http.ServeContent(ctx.Writer, ctx.Request, name, entry.MTime, bytes.NewReader(entry.Data))
 			return
 		}
+	}
 
-		s.logger.Info("Successfully returned file as raw data", "path", path)
-		ctx.Data(http.StatusOK, "raw", data)
+	if notModified(ctx, etag, modTime) {
+		s.logger.Info("Directory unchanged, skipping zip rebuild", "path", path)
+		return
+	}
+
+	data, err := backend.ZipDir(s.store, path)
+	if err != nil {
+		s.logger.Warn("Failed to zip dir", "error", err, "path", path)
+		ctx.String(http.StatusBadRequest, "Failed to zip dir '%s': %v", path, err)
+		return
 	}
+
+	ctx.Header("ETag", etag)
+	ctx.Header("Content-Type", "application/zip")
+	s.logger.Info("Successfully returned directory as zip", "path", path)
+	http.ServeContent(ctx.Writer, ctx.Request, name, modTime, bytes.NewReader(data))
+
 	if !s.noCache {
-		s.cache[path] = data
+		s.cache.Put(path, CacheEntry{Data: data, ETag: etag, MTime: modTime, Size: int64(len(data))})
 	}
 }
 
@@ -173,9 +330,17 @@ type File struct {
 func (s *Server) uploadFile(ctx *gin.Context) {
 	contentType := ctx.Request.Header.Get("content-type")
 	destAddr := ctx.Param("addr")
-	path := filepath.Join(s.rootDir, destAddr)
-	s.logger.Debug("Received upload file request",
-		"content-type", contentType, "destAddr", destAddr, "fullPath", path)
+	s.logger.Debug("Received upload file request", "content-type", contentType, "destAddr", destAddr)
+
+	if strings.HasPrefix(contentType, "multipart/form-data") {
+		if err := s.uploadMultipart(ctx, destAddr); err != nil {
+			s.handleStoreError(ctx, err, destAddr, "upload multipart data")
+			return
+		}
+
+		s.finishUpload(ctx, destAddr)
+		return
+	}
 
 	reqData, err := io.ReadAll(ctx.Request.Body)
 	if err != nil {
@@ -191,62 +356,133 @@ func (s *Server) uploadFile(ctx *gin.Context) {
 			return
 		}
 
-		err = zipdir.UnzipToDir(path, reqData)
-		if err != nil {
-			s.logger.Warn("Failed to unzip upload file request data", "error", err)
-			ctx.String(http.StatusBadRequest, err.Error())
+		if err = backend.UnzipToBackend(s.store, destAddr, reqData); err != nil {
+			s.handleStoreError(ctx, err, destAddr, "unzip upload data")
+			return
 		}
 	} else {
-		dir := path[:strings.LastIndex(path, "/")]
-		err = os.MkdirAll(dir, os.ModePerm)
+		file, err := s.store.Create(destAddr)
 		if err != nil {
-			s.logger.Warn("Failed to create dirs", "error", err, "dirs", dir)
-			ctx.String(http.StatusBadRequest, "Failed to make dirs '%s': %v", dir, err)
+			s.handleStoreError(ctx, err, destAddr, "open destination for upload")
 			return
 		}
 
-		file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_TRUNC, os.ModePerm)
+		_, err = file.Write(reqData)
+		closeErr := file.Close()
 		if err != nil {
-			if file != nil {
-				file.Close()
-			}
-
-			s.logger.Warn("Failed to create/truncate file during upload file request", "file", path, "error", err)
-			ctx.String(http.StatusBadRequest, "Failed to open file '%s': %v", path, err)
+			s.logger.Warn("Failed to write file data during upload file request", "error", err, "dest", destAddr)
+			ctx.String(http.StatusBadRequest, "Failed to write data to '%s': %v", destAddr, err)
 			return
 		}
+		if closeErr != nil {
+			s.logger.Warn("Failed to finalize upload", "error", closeErr, "dest", destAddr)
+			ctx.String(http.StatusBadRequest, "Failed to write data to '%s': %v", destAddr, closeErr)
+			return
+		}
+	}
 
-		_, err = file.Write(reqData)
-		file.Close()
+	s.finishUpload(ctx, destAddr)
+}
+
+/*
+uploadMultipart writes every file part of a multipart/form-data upload under
+destAddr, keyed by the part's own filename.
+*/
+func (s *Server) uploadMultipart(ctx *gin.Context, destAddr string) error {
+	reader, err := ctx.Request.MultipartReader()
+	if err != nil {
+		return err
+	}
+
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return nil
+		}
 		if err != nil {
-			s.logger.Warn("Failed to write file data during upload file request", "error", err, "file", path)
-			ctx.String(http.StatusBadRequest, "Failed to write data to file '%s': %v", path, err)
-			return
+			return err
+		}
+
+		if part.FileName() != "" {
+			err = s.uploadPart(destAddr, part)
+		}
+		part.Close()
+		if err != nil {
+			return err
 		}
 	}
+}
 
-	s.logger.Info("File data successfully uploaded", "path", path)
-	ctx.String(http.StatusOK, "Successfully wrote data to '%s'", path)
+func (s *Server) uploadPart(destAddr string, part *multipart.Part) error {
+	file, err := s.store.Create(path.Join(destAddr, part.FileName()))
+	if err != nil {
+		return err
+	}
+
+	if _, err = io.Copy(file, part); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func (s *Server) finishUpload(ctx *gin.Context, destAddr string) {
+	if !s.noCache {
+		s.cache.InvalidatePrefix(destAddr)
+	}
+
+	s.logger.Info("File data successfully uploaded", "path", destAddr)
+	ctx.String(http.StatusOK, "Successfully wrote data to '%s'", destAddr)
+}
+
+func (s *Server) deleteFile(ctx *gin.Context) {
+	addr := ctx.Param("addr")
+	s.logger.Debug("Received delete file request", "addr", addr)
+
+	if err := s.store.RemoveAll(addr); err != nil {
+		s.handleStoreError(ctx, err, addr, "delete")
+		return
+	}
+
+	if !s.noCache {
+		s.cache.InvalidatePrefix(addr)
+	}
+
+	s.logger.Info("Successfully deleted path", "addr", addr)
+	ctx.String(http.StatusOK, "Successfully deleted '%s'", addr)
 }
 
 func (s *Server) getEntries(ctx *gin.Context) {
 	relativePath := ctx.Param("addr")
-	path := filepath.Join(s.rootDir, relativePath)
-	entries, err := os.ReadDir(path)
+	entries, err := s.store.ReadDir(relativePath)
 	if err != nil {
-		s.logger.Warn("Failed to get entries in directory", "error", err, "dirPath", path)
-		ctx.String(http.StatusBadRequest, "Failed to read directory '%s': %v", path, err)
+		s.handleStoreError(ctx, err, relativePath, "read dir")
 		return
 	}
 
-	var entryNames []string
-	for _, entry := range entries {
-		entryNames = append(entryNames, entry.Name())
+	s.logger.Info("Successfully processed entries request", "addr", relativePath, "count", len(entries))
+	writeEntries(ctx, relativePath, entries, s.browseUI)
+}
+
+/*
+loadAuthTokens combines the inline tokens from opts with any loaded from
+their corresponding token files.
+*/
+func loadAuthTokens(opts Opts) (AuthTokens, error) {
+	fileReadWrite, err := loadTokenFile(opts.AuthTokensFile)
+	if err != nil {
+		return AuthTokens{}, fmt.Errorf("failed to read auth tokens file: %v", err)
+	}
+
+	fileReadOnly, err := loadTokenFile(opts.ReadOnlyTokensFile)
+	if err != nil {
+		return AuthTokens{}, fmt.Errorf("failed to read read-only auth tokens file: %v", err)
 	}
 
-	respString := strings.Join(entryNames, ",")
-	s.logger.Info("Successfully processed entries request", "entries", respString)
-	ctx.String(http.StatusOK, respString)
+	return AuthTokens{
+		ReadWrite: append(opts.AuthTokens, fileReadWrite...),
+		ReadOnly:  append(opts.ReadOnlyTokens, fileReadOnly...),
+	}, nil
 }
 
 func createLogWriter(level, logFile string) (hclog.Logger, error) {
@@ -273,6 +509,28 @@ func createLogWriter(level, logFile string) (hclog.Logger, error) {
 	}), nil
 }
 
+/*
+createBackend builds the Backend described by opts.Backend. An empty value
+defaults to a local-filesystem backend rooted at opts.RootDir.
+*/
+func createBackend(opts Opts) (backend.Backend, error) {
+	switch opts.Backend {
+	case "", "os":
+		if err := checkIsDir(opts.RootDir); err != nil {
+			return nil, fmt.Errorf("failed to read root dir: %v", err)
+		}
+		return backend.NewOSBackend(opts.RootDir), nil
+	case "s3":
+		store, err := backend.NewS3Backend(opts.S3Endpoint, opts.S3Bucket, opts.S3AccessKey, opts.S3SecretKey, true)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create s3 backend: %v", err)
+		}
+		return store, nil
+	default:
+		return nil, fmt.Errorf("unknown backend '%s'", opts.Backend)
+	}
+}
+
 func checkIsDir(dir string) error {
 	inf, err := os.Stat(dir)
 	if errors.Is(err, os.ErrNotExist) {
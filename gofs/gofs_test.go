@@ -2,35 +2,35 @@ package gofs
 
 import (
 	"fmt"
-	"github.com/phayes/freeport"
-	"github.com/stretchr/testify/assert"
 	"os"
 	"testing"
-)
 
-var addr string
+	"github.com/phayes/freeport"
+	"github.com/stretchr/testify/assert"
+)
 
 func TestCreate_NoRootDir(t *testing.T) {
 	opts := Opts{}
-	err := Create(opts)
+	_, err := Create(opts)
 	assert.NotNil(t, err)
 	if err != nil {
 		assert.Contains(t, err.Error(), "failed to read root dir")
 	}
 }
 
-func TestCreate_InvalidAddr(t *testing.T) {
+func TestCreate_UnknownBackend(t *testing.T) {
 	opts := Opts{
 		RootDir: os.TempDir(),
+		Backend: "bogus",
 	}
-	err := Create(opts)
+	_, err := Create(opts)
 	assert.NotNil(t, err)
 	if err != nil {
-		assert.Contains(t, err.Error(), "port not specified")
+		assert.Contains(t, err.Error(), "unknown backend")
 	}
 }
 
-func setupTestServer(t *testing.T) {
+func setupTestServer(t *testing.T) (*Server, string) {
 	port, err := freeport.GetFreePort()
 	if err != nil {
 		t.Fatalf("Failed to get free port: %v", err)
@@ -42,9 +42,15 @@ func setupTestServer(t *testing.T) {
 		LogLevel: "TRACE",
 		RootDir:  os.TempDir(),
 	}
-	err = Create(opts)
+	server, err := Create(opts)
 	if err != nil {
 		t.Fatalf("Failed to create server: %v", err)
 	}
-	addr = fmt.Sprintf("127.0.0.1:%d", port)
+	return server, fmt.Sprintf("127.0.0.1:%d", port)
+}
+
+func TestCreate_Valid(t *testing.T) {
+	server, addr := setupTestServer(t)
+	assert.NotNil(t, server)
+	assert.NotEmpty(t, addr)
 }
@@ -0,0 +1,110 @@
+package gofs
+
+import (
+	"fmt"
+	"html"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/ahdekkers/gofs/gofs/backend"
+	"github.com/gin-gonic/gin"
+)
+
+/*
+entryJSON is the JSON representation of a single directory entry returned
+by getEntries.
+*/
+type entryJSON struct {
+	Name  string `json:"name"`
+	Size  int64  `json:"size"`
+	MTime int64  `json:"mtime"`
+	IsDir bool   `json:"isDir"`
+	ETag  string `json:"etag"`
+}
+
+/*
+writeEntries content-negotiates on the request's Accept header and writes
+entries in the corresponding format: JSON for programmatic clients, an
+HTML index page for browsers (when browseUI is enabled), or a plain
+newline-separated list otherwise.
+*/
+func writeEntries(ctx *gin.Context, addr string, entries []backend.Info, browseUI bool) {
+	switch negotiateListingFormat(ctx, browseUI) {
+	case listingFormatJSON:
+		writeEntriesJSON(ctx, entries)
+	case listingFormatHTML:
+		writeEntriesHTML(ctx, addr, entries)
+	default:
+		writeEntriesText(ctx, entries)
+	}
+}
+
+type listingFormat int
+
+const (
+	listingFormatText listingFormat = iota
+	listingFormatJSON
+	listingFormatHTML
+)
+
+func negotiateListingFormat(ctx *gin.Context, browseUI bool) listingFormat {
+	switch ctx.NegotiateFormat(gin.MIMEJSON, gin.MIMEHTML, gin.MIMEPlain) {
+	case gin.MIMEJSON:
+		return listingFormatJSON
+	case gin.MIMEHTML:
+		if browseUI {
+			return listingFormatHTML
+		}
+	}
+	return listingFormatText
+}
+
+func writeEntriesJSON(ctx *gin.Context, entries []backend.Info) {
+	body := make([]entryJSON, 0, len(entries))
+	for _, entry := range entries {
+		body = append(body, entryJSON{
+			Name:  entry.Name,
+			Size:  entry.Size,
+			MTime: entry.ModTime.Unix(),
+			IsDir: entry.IsDir,
+			ETag:  entryETag(entry),
+		})
+	}
+	ctx.JSON(http.StatusOK, body)
+}
+
+func writeEntriesText(ctx *gin.Context, entries []backend.Info) {
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, entry.Name)
+	}
+	ctx.String(http.StatusOK, strings.Join(names, "\n"))
+}
+
+/*
+writeEntriesHTML renders a minimal directory index, in the style of
+http.FileServer, with each entry linking to its path under /content/.
+*/
+func writeEntriesHTML(ctx *gin.Context, addr string, entries []backend.Info) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "<!DOCTYPE html>\n<html>\n<head><title>Index of %s</title></head>\n<body>\n", html.EscapeString(addr))
+	fmt.Fprintf(&b, "<h1>Index of %s</h1>\n<ul>\n", html.EscapeString(addr))
+
+	for _, entry := range entries {
+		name := entry.Name
+		if entry.IsDir {
+			name += "/"
+		}
+
+		href := "/content/" + path.Join(addr, entry.Name)
+		if entry.IsDir {
+			href += "/"
+		}
+
+		fmt.Fprintf(&b, "<li><a href=\"%s\">%s</a></li>\n", html.EscapeString(href), html.EscapeString(name))
+	}
+
+	b.WriteString("</ul>\n</body>\n</html>\n")
+	ctx.Data(http.StatusOK, "text/html; charset=utf-8", []byte(b.String()))
+}
@@ -0,0 +1,50 @@
+package gofs
+
+import (
+	"fmt"
+	"net/http"
+
+	"golang.org/x/crypto/acme/autocert"
+)
+
+type tlsMode int
+
+const (
+	tlsModeNone tlsMode = iota
+	tlsModeManual
+	tlsModeAuto
+)
+
+/*
+configureTLS wires srv up for one of plain HTTP, a static cert/key pair, or
+Let's Encrypt autocert, based on opts. When autocert is in use it also
+returns the HTTP-01 challenge server that must be run alongside srv on :80.
+*/
+func configureTLS(srv *http.Server, opts Opts) (mode tlsMode, cert string, key string, challengeSrv *http.Server, err error) {
+	switch {
+	case len(opts.AutoTLSHosts) > 0:
+		cacheDir := opts.AutoTLSCacheDir
+		if cacheDir == "" {
+			cacheDir = "autocert-cache"
+		}
+
+		manager := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(opts.AutoTLSHosts...),
+			Cache:      autocert.DirCache(cacheDir),
+		}
+		srv.TLSConfig = manager.TLSConfig()
+
+		challengeSrv = &http.Server{
+			Addr:    ":80",
+			Handler: manager.HTTPHandler(nil),
+		}
+		return tlsModeAuto, "", "", challengeSrv, nil
+	case opts.TLSCert != "" && opts.TLSKey != "":
+		return tlsModeManual, opts.TLSCert, opts.TLSKey, nil, nil
+	case opts.TLSCert != "" || opts.TLSKey != "":
+		return tlsModeNone, "", "", nil, fmt.Errorf("both tlsCert and tlsKey must be set")
+	default:
+		return tlsModeNone, "", "", nil, nil
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"github.com/spf13/cobra"
 	"log"
 	"net/http"
+	"time"
 )
 
 func main() {
@@ -17,6 +18,7 @@ func main() {
 			server, err := gofs.Create(opts)
 			if err != nil {
 				cmd.Printf("Error while creating gofs server: %v\n", err)
+				return
 			}
 
 			if err := server.Run(); err != nil && err != http.ErrServerClosed {
@@ -38,8 +40,44 @@ func main() {
 		"A file to write log output to, as well as stdOut")
 	flags.BoolVar(&opts.NoCache, "noCache", false,
 		"Whether or not to cache returned data for future requests")
+	flags.Int64Var(&opts.CacheBytes, "cacheBytes", 256*1024*1024,
+		"The maximum total size, in bytes, of cached file/zip data. 0 means unbounded")
+	flags.IntVar(&opts.CacheEntries, "cacheEntries", 1024,
+		"The maximum number of entries held in the cache. 0 means unbounded")
 	flags.BoolVar(&opts.NoDirectories, "noDirs", false,
 		"Whether to allow returning directories as zip data or not")
+	flags.StringVar(&opts.Backend, "backend", "os",
+		"The storage backend to serve files from: 'os' for a local directory, or 's3' for an S3-compatible bucket")
+	flags.StringVar(&opts.S3Endpoint, "s3Endpoint", "",
+		"The endpoint of the S3-compatible service, required when --backend=s3")
+	flags.StringVar(&opts.S3Bucket, "s3Bucket", "",
+		"The bucket to serve and accept uploads against, required when --backend=s3")
+	flags.StringVar(&opts.S3AccessKey, "s3AccessKey", "",
+		"The access key used to authenticate with the S3-compatible service")
+	flags.StringVar(&opts.S3SecretKey, "s3SecretKey", "",
+		"The secret key used to authenticate with the S3-compatible service")
+	flags.StringVar(&opts.TLSCert, "tlsCert", "",
+		"Path to a TLS certificate file. Serves over HTTPS when set along with --tlsKey")
+	flags.StringVar(&opts.TLSKey, "tlsKey", "",
+		"Path to a TLS private key file. Serves over HTTPS when set along with --tlsCert")
+	flags.StringSliceVar(&opts.AutoTLSHosts, "letsEncryptHosts", nil,
+		"Hostnames to request Let's Encrypt certificates for. When set, gofs serves HTTPS on --port and HTTP-01 challenges on :80, taking precedence over --tlsCert/--tlsKey")
+	flags.StringVar(&opts.AutoTLSCacheDir, "letsEncryptCacheDir", "",
+		"Directory to cache Let's Encrypt certificates in. Defaults to './autocert-cache'")
+	flags.StringSliceVar(&opts.AuthTokens, "authTokens", nil,
+		"Bearer tokens that grant read-write access. Once set, uploads and deletes require one of these tokens, a signed URL, or a valid read-only token (for GETs)")
+	flags.StringVar(&opts.AuthTokensFile, "authTokensFile", "",
+		"A file of newline-separated bearer tokens, merged with --authTokens")
+	flags.StringSliceVar(&opts.ReadOnlyTokens, "readOnlyTokens", nil,
+		"Bearer tokens that grant read-only access. Once set, GETs also require one of these tokens, a read-write token, or a signed URL")
+	flags.StringVar(&opts.ReadOnlyTokensFile, "readOnlyTokensFile", "",
+		"A file of newline-separated read-only bearer tokens, merged with --readOnlyTokens")
+	flags.StringVar(&opts.SigningKey, "signingKey", "",
+		"Secret key used to verify HMAC-signed URLs (see the 'gofs sign' subcommand), accepted as an alternative to a bearer token")
+	flags.BoolVar(&opts.BrowseUI, "browse", false,
+		"Whether to serve an HTML directory index when a browser requests /entries")
+
+	rootCmd.AddCommand(newSignCmd())
 
 	err := rootCmd.MarkFlagRequired("rootDir")
 	if err != nil {
@@ -63,3 +101,37 @@ func main() {
 		log.Printf("[ERROR] Failed to execute main command: %v\n", err)
 	}
 }
+
+/*
+newSignCmd builds the 'gofs sign' subcommand, which prints a signed URL
+query string for a given path and TTL, for use with --signingKey.
+*/
+func newSignCmd() *cobra.Command {
+	var (
+		signingKey string
+		method     string
+		ttl        time.Duration
+	)
+
+	cmd := &cobra.Command{
+		Use:   "sign <path>",
+		Short: "Print a signed URL for a path, for use with --signingKey",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if signingKey == "" {
+				cmd.PrintErrln("Error: --signingKey is required")
+				return
+			}
+
+			qs := gofs.SignPath(signingKey, method, args[0], ttl)
+			cmd.Printf("%s?%s\n", args[0], qs)
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.StringVar(&signingKey, "signingKey", "", "The signing key configured on the server via --signingKey")
+	flags.StringVar(&method, "method", http.MethodGet, "The HTTP method the signed URL grants access to")
+	flags.DurationVar(&ttl, "ttl", time.Hour, "How long the signed URL remains valid for")
+
+	return cmd
+}